@@ -0,0 +1,161 @@
+package memfs
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Directory is a tree node holding named children plus whatever metadata a
+// builder recovered about the directory itself - a BSD mtree entry, for
+// instance, commonly carries mode/size/digest even for type=dir. Children
+// is never nil on a Directory returned by this package.
+type Directory struct {
+	Children map[string]interface{}
+	Mode     os.FileMode
+	Size     int64
+	Digest   string
+}
+
+// newDirectory returns an empty, ready-to-use Directory.
+func newDirectory() Directory {
+	return Directory{Children: map[string]interface{}{}}
+}
+
+// File is a tree leaf node holding a regular file's content and whatever
+// metadata the builder that produced it was able to recover - Mode, Size
+// and Digest are the zero value when unknown.
+type File struct {
+	Content []byte
+	Mode    os.FileMode
+	Size    int64
+	Digest  string
+}
+
+// Meta carries the optional per-node metadata a richer tree builder, such as
+// Mtree, is able to recover from its input. Zero values mean "not present in
+// the source".
+type Meta struct {
+	Mode   os.FileMode
+	Size   int64
+	Digest string
+	Link   string
+}
+
+// FS is an in-memory filesystem, represented as a tree of Directory, File
+// and Symlink nodes rooted at Tree.
+type FS struct {
+	Tree Directory
+}
+
+// MkdirAll creates every directory named by p that does not yet exist,
+// analogous to os.MkdirAll, and records mode on the final path component.
+// An empty path or "." addresses the FS root, which always exists.
+func (fs *FS) MkdirAll(p string, mode os.FileMode) error {
+	return fs.mkdirAll(p, Meta{Mode: mode})
+}
+
+// mkdirAll is MkdirAll's implementation, additionally recording size and
+// digest metadata on the final path component - used by builders, such as
+// Mtree, that recover more than just a mode for a directory.
+func (fs *FS) mkdirAll(p string, meta Meta) error {
+	if fs.Tree.Children == nil {
+		fs.Tree.Children = map[string]interface{}{}
+	}
+	names := splitPath(p)
+	if len(names) == 0 {
+		fs.Tree.Mode, fs.Tree.Size, fs.Tree.Digest = meta.Mode, meta.Size, meta.Digest
+		return nil
+	}
+	parent := fs.Tree.Children
+	for i, name := range names {
+		d := newDirectory()
+		if child, ok := parent[name]; ok {
+			var isDir bool
+			if d, isDir = child.(Directory); !isDir {
+				return &os.PathError{Op: "mkdir", Path: p, Err: os.ErrExist}
+			}
+		}
+		if i == len(names)-1 {
+			d.Mode, d.Size, d.Digest = meta.Mode, meta.Size, meta.Digest
+		}
+		parent[name] = d
+		parent = d.Children
+	}
+	return nil
+}
+
+// lookup returns the Directory addressed by p, or a *os.PathError when p
+// names a non-existent node or one that isn't a Directory.
+func (fs *FS) lookup(p string) (Directory, *os.PathError) {
+	dir := fs.Tree
+	for _, name := range splitPath(p) {
+		if dir.Children == nil {
+			return Directory{}, &os.PathError{Op: "open", Path: p, Err: os.ErrNotExist}
+		}
+		child, ok := dir.Children[name]
+		if !ok {
+			return Directory{}, &os.PathError{Op: "open", Path: p, Err: os.ErrNotExist}
+		}
+		d, ok := child.(Directory)
+		if !ok {
+			return Directory{}, &os.PathError{Op: "open", Path: p, Err: os.ErrInvalid}
+		}
+		dir = d
+	}
+	return dir, nil
+}
+
+// set inserts value as a leaf node at path, creating any missing parent
+// directories along the way.
+func (fs *FS) set(p string, value interface{}) error {
+	dir, base := filepath.Split(p)
+	if dir != "" {
+		if err := fs.ensureDir(dir); err != nil {
+			return err
+		}
+	}
+	parent, err := fs.lookup(dir)
+	if err != nil {
+		return err
+	}
+	parent.Children[base] = value
+	return nil
+}
+
+// ensureDir creates every directory named by p that does not yet exist,
+// same as mkdirAll, but never touches the metadata of a directory - new or
+// pre-existing - along the way. set uses it to guarantee a leaf's parent
+// path exists without stamping arbitrary metadata onto directories nothing
+// actually described.
+func (fs *FS) ensureDir(p string) error {
+	if fs.Tree.Children == nil {
+		fs.Tree.Children = map[string]interface{}{}
+	}
+	parent := fs.Tree.Children
+	for _, name := range splitPath(p) {
+		child, ok := parent[name]
+		if !ok {
+			d := newDirectory()
+			parent[name] = d
+			parent = d.Children
+			continue
+		}
+		d, isDir := child.(Directory)
+		if !isDir {
+			return &os.PathError{Op: "mkdir", Path: p, Err: os.ErrExist}
+		}
+		parent = d.Children
+	}
+	return nil
+}
+
+// splitPath breaks a filepath.Separator-delimited path into its non-empty
+// components, treating "", "." and the separator itself as the root.
+func splitPath(p string) []string {
+	p = filepath.Clean(p)
+	if p == "." || p == string(filepath.Separator) {
+		return nil
+	}
+	return strings.Split(strings.Trim(p, string(filepath.Separator)), string(filepath.Separator))
+}
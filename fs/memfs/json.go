@@ -0,0 +1,185 @@
+package memfs
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// jsonFile is the "long" on-the-wire form of a File, used whenever a mode
+// needs to be recorded: {"content": "...", "mode": 420}. Content is
+// base64-encoded, following encoding/json's usual []byte handling.
+type jsonFile struct {
+	Content []byte `json:"content"`
+	Mode    uint32 `json:"mode,omitempty"`
+}
+
+// jsonSymlink is the on-the-wire representation of a Symlink.
+type jsonSymlink struct {
+	Symlink string `json:"symlink"`
+}
+
+// jsonDir is the "long" on-the-wire form of a Directory, used whenever it
+// carries metadata of its own: {"children": {...}, "mode": 493}. Without
+// this form a Directory marshals as a bare object keyed by child name, same
+// as before metadata existed, so trees without directory metadata keep
+// their existing on-the-wire shape.
+type jsonDir struct {
+	Children map[string]json.RawMessage `json:"children"`
+	Mode     uint32                     `json:"mode,omitempty"`
+	Size     int64                      `json:"size,omitempty"`
+	Digest   string                     `json:"digest,omitempty"`
+}
+
+// MarshalJSON encodes fs as a recursive JSON object: directories are
+// objects keyed by child name, or - when they carry mode/size/digest
+// metadata of their own - a {"children", "mode", "size", "digest"} object;
+// files are null (empty, no mode), a base64 string (content, no mode) or a
+// {"content", "mode"} object; symlinks are a {"symlink": "target"} object.
+func (fs FS) MarshalJSON() ([]byte, error) {
+	return marshalNode(fs.Tree)
+}
+
+func marshalNode(node interface{}) ([]byte, error) {
+	switch node := node.(type) {
+	case Directory:
+		m := make(map[string]json.RawMessage, len(node.Children))
+		for name, child := range node.Children {
+			b, err := marshalNode(child)
+			if err != nil {
+				return nil, err
+			}
+			m[name] = b
+		}
+		if node.Mode == 0 && node.Size == 0 && node.Digest == "" {
+			return json.Marshal(m)
+		}
+		return json.Marshal(jsonDir{
+			Children: m,
+			Mode:     uint32(node.Mode),
+			Size:     node.Size,
+			Digest:   node.Digest,
+		})
+	case File:
+		if node.Mode == 0 {
+			if len(node.Content) == 0 {
+				return json.Marshal(nil)
+			}
+			return json.Marshal(base64.StdEncoding.EncodeToString(node.Content))
+		}
+		return json.Marshal(jsonFile{Content: node.Content, Mode: uint32(node.Mode)})
+	case Symlink:
+		return json.Marshal(jsonSymlink{Symlink: node.Target})
+	default:
+		return nil, fmt.Errorf("memfs: cannot marshal node of type %T", node)
+	}
+}
+
+// UnmarshalJSON decodes p, in the format produced by MarshalJSON, into fs.
+func (fs *FS) UnmarshalJSON(p []byte) error {
+	node, err := unmarshalNode(p)
+	if err != nil {
+		return err
+	}
+	dir, ok := node.(Directory)
+	if !ok {
+		return fmt.Errorf("memfs: root JSON node must be an object")
+	}
+	fs.Tree = dir
+	return nil
+}
+
+// unmarshalDir builds a Directory from a JSON object's fields, used for both
+// the bare short form and the long form's "children" field.
+func unmarshalDir(obj map[string]json.RawMessage) (Directory, error) {
+	dir := newDirectory()
+	for name, child := range obj {
+		node, err := unmarshalNode(child)
+		if err != nil {
+			return Directory{}, err
+		}
+		dir.Children[name] = node
+	}
+	return dir, nil
+}
+
+func unmarshalNode(raw json.RawMessage) (interface{}, error) {
+	switch trimmed := bytes.TrimSpace(raw); {
+	case bytes.Equal(trimmed, []byte("null")):
+		return File{}, nil
+	case len(trimmed) > 0 && trimmed[0] == '"':
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return nil, err
+		}
+		content, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return nil, err
+		}
+		return File{Content: content}, nil
+	case len(trimmed) > 0 && trimmed[0] == '{':
+		var obj map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &obj); err != nil {
+			return nil, err
+		}
+		if _, ok := obj["content"]; ok {
+			var jf jsonFile
+			if err := json.Unmarshal(raw, &jf); err != nil {
+				return nil, err
+			}
+			return File{Content: jf.Content, Mode: os.FileMode(jf.Mode)}, nil
+		}
+		if _, ok := obj["symlink"]; ok {
+			var js jsonSymlink
+			if err := json.Unmarshal(raw, &js); err != nil {
+				return nil, err
+			}
+			return Symlink{Target: js.Symlink}, nil
+		}
+		if _, ok := obj["children"]; ok {
+			var jd jsonDir
+			if err := json.Unmarshal(raw, &jd); err != nil {
+				return nil, err
+			}
+			dir, err := unmarshalDir(jd.Children)
+			if err != nil {
+				return nil, err
+			}
+			dir.Mode, dir.Size, dir.Digest = os.FileMode(jd.Mode), jd.Size, jd.Digest
+			return dir, nil
+		}
+		return unmarshalDir(obj)
+	default:
+		return nil, fmt.Errorf("memfs: invalid JSON tree node: %s", raw)
+	}
+}
+
+// JSON is a CustomTree-compatible tree builder for the JSON tree format
+// produced by FS.MarshalJSON. Unlike Unix and Tab it consumes the whole
+// reader at once instead of one line at a time, since a JSON tree isn't
+// line-oriented.
+var JSON jsonTree
+
+type jsonTree struct{}
+
+// Tree builds FS.Tree from r, which must contain a JSON tree as produced by
+// FS.MarshalJSON.
+func (jsonTree) Tree(r io.Reader) (fs FS, err error) {
+	p, err := ioutil.ReadAll(r)
+	if err != nil {
+		return FS{}, err
+	}
+	err = fs.UnmarshalJSON(p)
+	return
+}
+
+// JSONTree builds FS.Tree from a buffer that contains a JSON tree.
+//
+// JSONTree(p) is a short alternative to JSON.Tree(bytes.NewReader(p)).
+func JSONTree(p []byte) (FS, error) {
+	return JSON.Tree(bytes.NewReader(p))
+}
@@ -0,0 +1,54 @@
+package memfs
+
+import (
+	"os"
+	"testing"
+)
+
+func TestFSMarshalJSONSymlink(t *testing.T) {
+	fs := FS{Tree: Directory{Children: map[string]interface{}{
+		"link": Symlink{Target: "target.txt"},
+	}}}
+	p, err := fs.MarshalJSON()
+	if err != nil {
+		t.Fatalf("MarshalJSON()=%v", err)
+	}
+	var got FS
+	if err := got.UnmarshalJSON(p); err != nil {
+		t.Fatalf("UnmarshalJSON()=%v", err)
+	}
+	link, ok := got.Tree.Children["link"].(Symlink)
+	if !ok {
+		t.Fatalf("got.Tree.Children[\"link\"]=%#v, want Symlink", got.Tree.Children["link"])
+	}
+	if link.Target != "target.txt" {
+		t.Errorf("link.Target=%q, want %q", link.Target, "target.txt")
+	}
+}
+
+func TestFSMarshalJSONDirectoryMetadata(t *testing.T) {
+	fs := FS{Tree: Directory{
+		Mode: 0755,
+		Children: map[string]interface{}{
+			"usr": Directory{Mode: 0700, Children: map[string]interface{}{}},
+		},
+	}}
+	p, err := fs.MarshalJSON()
+	if err != nil {
+		t.Fatalf("MarshalJSON()=%v", err)
+	}
+	var got FS
+	if err := got.UnmarshalJSON(p); err != nil {
+		t.Fatalf("UnmarshalJSON()=%v", err)
+	}
+	if got.Tree.Mode != 0755 {
+		t.Errorf("got.Tree.Mode=%v, want %v", got.Tree.Mode, os.FileMode(0755))
+	}
+	usr, ok := got.Tree.Children["usr"].(Directory)
+	if !ok {
+		t.Fatalf("got.Tree.Children[\"usr\"]=%#v, want Directory", got.Tree.Children["usr"])
+	}
+	if usr.Mode != 0700 {
+		t.Errorf("usr.Mode=%v, want %v", usr.Mode, os.FileMode(0700))
+	}
+}
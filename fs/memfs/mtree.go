@@ -0,0 +1,154 @@
+package memfs
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// NodeType identifies the kind of filesystem node a TreeReader emits for a
+// single path.
+type NodeType int
+
+// Node types emitted by a TreeReader.
+const (
+	TypeFile NodeType = iota
+	TypeDir
+	TypeSymlink
+)
+
+// TreeReader is implemented by tree builders that need the whole input
+// available at once, rather than a single line like CustomTree, because
+// making sense of one entry depends on state accumulated from the ones
+// before it - mtree's "/set" defaults and its relative "." / ".." path
+// changes being the motivating example. ReadTree calls fn once per node, in
+// document order, stopping at the first error either from parsing or from
+// fn itself.
+type TreeReader interface {
+	ReadTree(r io.Reader, fn func(path string, typ NodeType, meta Meta) error) error
+}
+
+// Mtree is a TreeReader for BSD mtree "DirectoryHierarchy" specifications,
+// as produced by `mtree -c` and consumed by `mtree -p`.
+var Mtree TreeReader = mtree{}
+
+type mtree struct{}
+
+func (mtree) ReadTree(r io.Reader, fn func(path string, typ NodeType, meta Meta) error) error {
+	var (
+		scanner  = bufio.NewScanner(r)
+		defaults = map[string]string{}
+		stack    = []string{}
+	)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		fields := strings.Fields(string(line))
+		switch {
+		case fields[0] == "/set":
+			for _, kw := range fields[1:] {
+				k, v := splitKeyword(kw)
+				defaults[k] = v
+			}
+			continue
+		case fields[0] == "/unset":
+			for _, k := range fields[1:] {
+				delete(defaults, k)
+			}
+			continue
+		case fields[0] == "..":
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+			continue
+		}
+		name := fields[0]
+		kw := map[string]string{}
+		for k, v := range defaults {
+			kw[k] = v
+		}
+		for _, f := range fields[1:] {
+			k, v := splitKeyword(f)
+			kw[k] = v
+		}
+		typ := TypeFile
+		if kw["type"] == "dir" {
+			typ = TypeDir
+		} else if kw["type"] == "link" {
+			typ = TypeSymlink
+		}
+		var meta Meta
+		if kw["mode"] != "" {
+			if m, err := strconv.ParseUint(kw["mode"], 8, 32); err == nil {
+				meta.Mode = os.FileMode(m)
+			}
+		}
+		if kw["size"] != "" {
+			meta.Size, _ = strconv.ParseInt(kw["size"], 10, 64)
+		}
+		meta.Digest = kw["sha256digest"]
+		meta.Link = kw["link"]
+
+		if name == "." {
+			// The root itself carries no path component of its own, but an
+			// active /set still describes metadata for the FS root.
+			if err := fn(".", TypeDir, meta); err != nil {
+				return err
+			}
+			continue
+		}
+
+		p := filepath.Join(append(append([]string{}, stack...), name)...)
+		if err := fn(p, typ, meta); err != nil {
+			return err
+		}
+		if typ == TypeDir {
+			stack = append(stack, name)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func splitKeyword(kw string) (key, value string) {
+	if n := strings.IndexByte(kw, '='); n != -1 {
+		return kw[:n], kw[n+1:]
+	}
+	return kw, ""
+}
+
+// MtreeTree builds FS.Tree from a buffer containing a BSD mtree spec.
+//
+// MtreeTree(p) is a short alternative to buildTree(Mtree, bytes.NewReader(p)).
+func MtreeTree(p []byte) (FS, error) {
+	return buildTree(Mtree, bytes.NewReader(p))
+}
+
+// buildTree drains a TreeReader into an FS, creating directories and leaf
+// nodes as they're emitted.
+func buildTree(tr TreeReader, r io.Reader) (fs FS, err error) {
+	fs.Tree = newDirectory()
+	err = tr.ReadTree(r, func(p string, typ NodeType, meta Meta) error {
+		switch typ {
+		case TypeDir:
+			return fs.mkdirAll(p, meta)
+		case TypeSymlink:
+			return fs.set(p, Symlink{Target: meta.Link})
+		default:
+			return fs.set(p, File{Mode: meta.Mode, Size: meta.Size, Digest: meta.Digest})
+		}
+	})
+	if err != nil {
+		return FS{}, fmt.Errorf("memfs: mtree: %v", err)
+	}
+	return fs, nil
+}
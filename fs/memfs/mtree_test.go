@@ -0,0 +1,55 @@
+package memfs
+
+import (
+	"os"
+	"testing"
+)
+
+func TestMtreeTreeDirectoryMetadata(t *testing.T) {
+	spec := []byte(`#mtree
+/set type=dir mode=0755
+.
+usr type=dir mode=0700
+    bin type=file mode=0644 size=1024 sha256digest=deadbeef
+..
+..
+`)
+	fs, err := MtreeTree(spec)
+	if err != nil {
+		t.Fatalf("MtreeTree()=%v", err)
+	}
+	if fs.Tree.Mode != 0755 {
+		t.Errorf("fs.Tree.Mode=%v, want %v", fs.Tree.Mode, os.FileMode(0755))
+	}
+	usr, ok := fs.Tree.Children["usr"].(Directory)
+	if !ok {
+		t.Fatalf("fs.Tree.Children[\"usr\"]=%#v, want Directory", fs.Tree.Children["usr"])
+	}
+	if usr.Mode != 0700 {
+		t.Errorf("usr.Mode=%v, want %v", usr.Mode, os.FileMode(0700))
+	}
+	bin, ok := usr.Children["bin"].(File)
+	if !ok {
+		t.Fatalf("usr.Children[\"bin\"]=%#v, want File", usr.Children["bin"])
+	}
+	if bin.Mode != 0644 || bin.Size != 1024 || bin.Digest != "deadbeef" {
+		t.Errorf("bin=%#v, want Mode=0644 Size=1024 Digest=deadbeef", bin)
+	}
+}
+
+func TestMtreeTreeSymlink(t *testing.T) {
+	spec := []byte(`.
+link type=link link=target.txt
+`)
+	fs, err := MtreeTree(spec)
+	if err != nil {
+		t.Fatalf("MtreeTree()=%v", err)
+	}
+	link, ok := fs.Tree.Children["link"].(Symlink)
+	if !ok {
+		t.Fatalf("fs.Tree.Children[\"link\"]=%#v, want Symlink", fs.Tree.Children["link"])
+	}
+	if link.Target != "target.txt" {
+		t.Errorf("link.Target=%q, want %q", link.Target, "target.txt")
+	}
+}
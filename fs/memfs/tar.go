@@ -0,0 +1,76 @@
+package memfs
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+// Symlink is a tree node representing a symbolic or hard link. Target holds
+// the link's destination as stored in the archive or manifest that produced
+// it (a tar Linkname, an mtree "link=" keyword, etc.).
+type Symlink struct {
+	Target string
+}
+
+// TarTree builds FS.Tree by streaming a tar archive. It transparently
+// decompresses gzip-compressed input (tar.gz), so callers do not need to
+// know the archive's compression up front.
+//
+// Every header's Name is split on "/" and materialized as nested Directory
+// entries. Regular files become File values holding the entry's content,
+// mode and size; UnixTree and TabTree, by contrast, read names only and
+// build File values with no content. Symlinks and hardlinks are
+// represented as Symlink nodes carrying the link target, and directory
+// entries are always created even when they contain no children.
+func TarTree(r io.Reader) (fs FS, err error) {
+	fs.Tree = newDirectory()
+	br := bufio.NewReader(r)
+	if gz, gzerr := br.Peek(2); gzerr == nil && gz[0] == 0x1f && gz[1] == 0x8b {
+		var gzr *gzip.Reader
+		if gzr, err = gzip.NewReader(br); err != nil {
+			return
+		}
+		defer gzr.Close()
+		r = gzr
+	} else {
+		r = br
+	}
+	tr := tar.NewReader(r)
+	for {
+		var hdr *tar.Header
+		if hdr, err = tr.Next(); err == io.EOF {
+			err = nil
+			return
+		} else if err != nil {
+			return
+		}
+		name := filepath.FromSlash(strings.Trim(strings.TrimSuffix(hdr.Name, "/"), "/"))
+		if name == "" || name == "." {
+			continue
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err = fs.MkdirAll(name, hdr.FileInfo().Mode()); err != nil {
+				return
+			}
+		case tar.TypeSymlink, tar.TypeLink:
+			if err = fs.set(name, Symlink{Target: hdr.Linkname}); err != nil {
+				return
+			}
+		default:
+			var content []byte
+			if content, err = ioutil.ReadAll(tr); err != nil {
+				return
+			}
+			file := File{Content: content, Mode: hdr.FileInfo().Mode(), Size: hdr.Size}
+			if err = fs.set(name, file); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package memfs
+
+import (
+	"archive/tar"
+	"bytes"
+	"testing"
+)
+
+func writeTar(t *testing.T, hdrs []*tar.Header, content map[string][]byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, hdr := range hdrs {
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatalf("WriteHeader(%q)=%v", hdr.Name, err)
+		}
+		if p, ok := content[hdr.Name]; ok {
+			if _, err := tw.Write(p); err != nil {
+				t.Fatalf("Write(%q)=%v", hdr.Name, err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Close()=%v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestTarTree(t *testing.T) {
+	p := writeTar(t, []*tar.Header{
+		{Name: "dir/", Typeflag: tar.TypeDir, Mode: 0755},
+		{Name: "dir/file.txt", Typeflag: tar.TypeReg, Mode: 0644, Size: 5},
+		{Name: "dir/link.txt", Typeflag: tar.TypeSymlink, Linkname: "file.txt"},
+	}, map[string][]byte{
+		"dir/file.txt": []byte("hello"),
+	})
+	fs, err := TarTree(bytes.NewReader(p))
+	if err != nil {
+		t.Fatalf("TarTree()=%v", err)
+	}
+	dir, ok := fs.Tree.Children["dir"].(Directory)
+	if !ok {
+		t.Fatalf("fs.Tree.Children[\"dir\"]=%#v, want Directory", fs.Tree.Children["dir"])
+	}
+	file, ok := dir.Children["file.txt"].(File)
+	if !ok {
+		t.Fatalf("dir.Children[\"file.txt\"]=%#v, want File", dir.Children["file.txt"])
+	}
+	if string(file.Content) != "hello" {
+		t.Errorf("file.Content=%q, want %q", file.Content, "hello")
+	}
+	if file.Size != 5 {
+		t.Errorf("file.Size=%d, want 5", file.Size)
+	}
+	link, ok := dir.Children["link.txt"].(Symlink)
+	if !ok {
+		t.Fatalf("dir.Children[\"link.txt\"]=%#v, want Symlink", dir.Children["link.txt"])
+	}
+	if link.Target != "file.txt" {
+		t.Errorf("link.Target=%q, want %q", link.Target, "file.txt")
+	}
+}
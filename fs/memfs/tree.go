@@ -6,45 +6,112 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
-	"os"
 	"path/filepath"
+	"strconv"
 	"unicode"
+	"unicode/utf8"
 )
 
 // Box drawings symbols - http://unicode-table.com/en/sections/box-drawing/.
+// Only the ones UnixStrict's byte-counting still needs are kept; the
+// rune-based scanBoxPrefix used by the default Unix builder doesn't need
+// named symbols for the glyphs it recognizes.
 var (
-	boxVerticalRight = []byte("├")
-	boxHorizontal    = []byte("─")
-	boxVertical      = []byte("│")
-	boxUpRight       = []byte("└")
-	boxSpace         = []byte{'\u0020'}
-	boxHardSpace     = []byte{'\u00A0'}
+	boxHorizontal = []byte("─")
+	boxVertical   = []byte("│")
+	boxSpace      = []byte{'\u0020'}
+	boxHardSpace  = []byte{'\u00A0'}
 )
 
-func max(i, j int) int {
-	if i > j {
-		return i
-	}
-	return j
-}
-
 // CustomTree instructs tree builder how to parse single line of given buffer,
 // where 'name' is the name of a tree node, 'depth' is its depth in the tree
 // and 'err' eventual parsing failure. The 'line' is guaranteed to be non-nil
 // non-empty.
 type CustomTree func(line []byte) (depth int, name []byte, err error)
 
-// Unix is a tree builder for the 'tree' Unix command.
+// Unix is a tree builder for the 'tree' Unix command. It scans each line's
+// leading indentation rune-by-rune, so it copes with filenames that contain
+// spaces, box-drawing glyphs or U+2500 themselves, and it understands both
+// `tree -Q` (double-quoted, C-escaped names) and `tree --charset=ascii`
+// output ("|--", "`--", "|   "). Use UnixStrict for the previous, simpler
+// byte-counting behavior.
 var Unix CustomTree
 
+// UnixStrict is the original Unix tree builder, kept for callers that rely
+// on its exact (and more fragile) byte-counting depth calculation.
+var UnixStrict CustomTree
+
 // Tab is a tree builder for simplified tree representation, where each level
 // is idented with one tabulation character (\t) only.
 var Tab CustomTree
 
+// boxUnit checks whether p starts with one fixed-width, 4-rune box-drawing
+// indentation unit, as printed by both `tree` and `tree --charset=ascii`,
+// and reports its byte width plus whether it's a branch unit - the last one
+// before a name ("├── ", "└── ", "|-- ", "`-- ") - as opposed to a
+// continuation: an ancestor's still-open vertical bar, or the gap left by
+// an already-closed one ("│   ", "|   ", "    "). Only a whole, fixed unit
+// is ever consumed, so an indentation-alphabet rune that's actually part of
+// a name (a leading space or "-") is left for name itself to keep.
+func boxUnit(p []byte) (width int, branch, ok bool) {
+	var runes [4]rune
+	i := 0
+	for n := range runes {
+		if i >= len(p) {
+			return 0, false, false
+		}
+		r, size := utf8.DecodeRune(p[i:])
+		runes[n] = r
+		i += size
+	}
+	switch runes {
+	case [4]rune{'│', ' ', ' ', ' '}, [4]rune{'|', ' ', ' ', ' '}, [4]rune{' ', ' ', ' ', ' '}:
+		return i, false, true
+	case [4]rune{'├', '─', '─', ' '}, [4]rune{'└', '─', '─', ' '},
+		[4]rune{'|', '-', '-', ' '}, [4]rune{'`', '-', '-', ' '}:
+		return i, true, true
+	}
+	return 0, false, false
+}
+
+// scanBoxPrefix consumes the leading run of boxUnit indentation units,
+// stopping as soon as a branch unit is consumed, or at the first rune that
+// doesn't start one. depth is the number of units consumed (one per tree
+// level) and rest is p with that prefix removed.
+func scanBoxPrefix(p []byte) (depth int, rest []byte) {
+	i := 0
+	for {
+		width, branch, ok := boxUnit(p[i:])
+		if !ok {
+			break
+		}
+		i += width
+		depth++
+		if branch {
+			break
+		}
+	}
+	return depth, p[i:]
+}
+
 func init() {
 	Unix = func(p []byte) (depth int, name []byte, err error) {
+		var rest []byte
+		depth, rest = scanBoxPrefix(p)
+		if depth == 0 || len(rest) == 0 {
+			err = fmt.Errorf("invalid syntax: %q", p)
+			return
+		}
+		name = rest
+		if name[0] == '"' {
+			if s, uerr := strconv.Unquote(string(name)); uerr == nil {
+				name = []byte(s)
+			}
+		}
+		return
+	}
+	UnixStrict = func(p []byte) (depth int, name []byte, err error) {
 		var n int
-		// TODO(rjeczalik): Count up to first non-box character.
 		depth = (bytes.Count(p, boxSpace) + bytes.Count(p, boxHardSpace) +
 			bytes.Count(p, boxVertical)) / 4
 		if n = bytes.LastIndex(p, boxHorizontal); n == -1 {
@@ -66,40 +133,44 @@ func init() {
 	}
 }
 
-// Tree builds FS.Tree from given reader using CustomTree callback for parsing
-// node's name and its depth in the tree.
-func (ct CustomTree) Tree(r io.Reader) (fs FS, err error) {
+// Walk parses the same input formats as Tree, but instead of materializing
+// the whole FS it invokes fn for each node as soon as its type is known -
+// that is, once the following line has established whether the node is a
+// directory or a file. Walk stops and returns the first non-nil error from
+// reading r or returned by fn, so a caller can short-circuit the walk. An
+// error that ct reports for a line after the first is not returned; Walk
+// goes on with the next line.
+func (ct CustomTree) Walk(r io.Reader, fn func(path string, isDir bool) error) error {
+	// frame is a directory pushed onto the ancestor stack, remembering the
+	// depth it was seen at so the stack can be popped by comparing depths
+	// rather than assuming they start at 0 - Unix's depth, for instance,
+	// starts at 1 for the root's immediate children.
+	type frame struct {
+		depth int
+		name  string
+	}
 	var (
-		dir       = Directory{}
 		buf       = bufio.NewReader(r)
-		glob      []Directory
+		root      string
+		stack     []frame
 		name      []byte
 		prevName  []byte
 		depth     int
 		prevDepth int
 	)
-	fs.Tree = dir
 	line, err := buf.ReadBytes('\n')
 	if len(line) == 0 || err == io.EOF {
-		err = io.ErrUnexpectedEOF
-		return
+		return io.ErrUnexpectedEOF
 	}
 	if err != nil {
-		return
+		return err
 	}
 	if len(line) != 1 || line[0] != '.' {
-		p := filepath.FromSlash(string(bytes.TrimSpace(line)))
-		if err = fs.MkdirAll(p, 0); err != nil {
-			return
-		}
-		// TODO(rjeczalik): make it an exported helper method
-		var perr *os.PathError
-		if dir, perr = fs.lookup(p); perr != nil {
-			err = perr
-			return
+		root = filepath.FromSlash(string(bytes.TrimSpace(line)))
+		if err := fn(root, true); err != nil {
+			return err
 		}
 	}
-	glob = append(glob, dir)
 	for {
 		line, err = buf.ReadBytes('\n')
 		if len(bytes.TrimSpace(line)) == 0 {
@@ -110,26 +181,38 @@ func (ct CustomTree) Tree(r io.Reader) (fs FS, err error) {
 		}
 		// Skip first iteration.
 		if len(prevName) != 0 {
-			// Insert the node from previous iteration - node is a directory when
-			// a diference of the tree depth > 0, a file otherwise.
+			// The node from the previous iteration is a directory when the
+			// difference of the tree depth > 0, or its name is suffixed
+			// with a "/", a file otherwise.
 			var (
-				name  string
-				value interface{}
+				base  string
+				isDir bool
 			)
 			if bytes.HasSuffix(prevName, []byte{'/'}) {
-				name, value = string(bytes.TrimRight(prevName, "/")), Directory{}
+				base, isDir = string(bytes.TrimRight(prevName, "/")), true
 			} else {
-				name, value = string(prevName), File{}
+				base = string(prevName)
 			}
-			switch {
-			case depth > prevDepth:
-				d := Directory{}
-				dir[name], glob, dir = d, append(glob, dir), d
-			case depth == prevDepth:
-				dir[name] = value
-			case depth < prevDepth:
-				n := max(len(glob)+depth-prevDepth, 0)
-				dir[name], dir, glob = value, glob[n], glob[:n]
+			if depth > prevDepth {
+				isDir = true
+			}
+			// Pop every frame that isn't an ancestor of prevName, i.e.
+			// everything at prevName's depth or deeper.
+			for len(stack) > 0 && stack[len(stack)-1].depth >= prevDepth {
+				stack = stack[:len(stack)-1]
+			}
+			parts := make([]string, 0, len(stack)+2)
+			parts = append(parts, root)
+			for _, f := range stack {
+				parts = append(parts, f.name)
+			}
+			parts = append(parts, base)
+			p := filepath.Join(parts...)
+			if err := fn(p, isDir); err != nil {
+				return err
+			}
+			if isDir {
+				stack = append(stack, frame{prevDepth, base})
 			}
 		}
 		// A node from each iteration is handled on the next one. That's why the
@@ -138,12 +221,26 @@ func (ct CustomTree) Tree(r io.Reader) (fs FS, err error) {
 			if err == io.EOF {
 				err = nil
 			}
-			return
+			return err
 		}
 		prevDepth, prevName = depth, name
 	}
 }
 
+// Tree builds FS.Tree from given reader using CustomTree callback for parsing
+// node's name and its depth in the tree. It is a thin wrapper around Walk
+// that accumulates the emitted nodes into an FS.
+func (ct CustomTree) Tree(r io.Reader) (fs FS, err error) {
+	fs.Tree = newDirectory()
+	err = ct.Walk(r, func(p string, isDir bool) error {
+		if isDir {
+			return fs.MkdirAll(p, 0)
+		}
+		return fs.set(p, File{})
+	})
+	return
+}
+
 // UnixTree builds FS.Tree from a buffer that contains tree-like (Unix command) output.
 //
 // Example:
@@ -0,0 +1,71 @@
+package memfs
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUnixTreeSameDepthSiblings(t *testing.T) {
+	cases := map[string]struct {
+		tree []byte
+		want Directory
+	}{
+		"flat siblings": {
+			tree: []byte(".\n├── file1\n└── file2"),
+			want: Directory{Children: map[string]interface{}{
+				"file1": File{},
+				"file2": File{},
+			}},
+		},
+		"siblings across two directories": {
+			tree: []byte(".\n├── dir1\n│   ├── a.txt\n│   └── b.txt\n└── dir2\n    └── c.txt"),
+			want: Directory{Children: map[string]interface{}{
+				"dir1": Directory{Children: map[string]interface{}{
+					"a.txt": File{},
+					"b.txt": File{},
+				}},
+				"dir2": Directory{Children: map[string]interface{}{
+					"c.txt": File{},
+				}},
+			}},
+		},
+	}
+	for name, cas := range cases {
+		t.Run(name, func(t *testing.T) {
+			fs, err := UnixTree(cas.tree)
+			if err != nil {
+				t.Fatalf("UnixTree()=%v", err)
+			}
+			if !reflect.DeepEqual(fs.Tree, cas.want) {
+				t.Errorf("got %#v, want %#v", fs.Tree, cas.want)
+			}
+		})
+	}
+}
+
+func TestUnixTreeNamesStartingWithIndentAlphabetRunes(t *testing.T) {
+	cases := map[string]struct {
+		tree []byte
+		want string
+	}{
+		"leading hyphen": {
+			tree: []byte(".\n└── -weirdname\n    └── file.txt"),
+			want: "-weirdname",
+		},
+		"leading space": {
+			tree: []byte(".\n├──  leadingspace.txt"),
+			want: " leadingspace.txt",
+		},
+	}
+	for name, cas := range cases {
+		t.Run(name, func(t *testing.T) {
+			fs, err := UnixTree(cas.tree)
+			if err != nil {
+				t.Fatalf("UnixTree()=%v", err)
+			}
+			if _, ok := fs.Tree.Children[cas.want]; !ok {
+				t.Errorf("got %#v, want a child named %q", fs.Tree.Children, cas.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+// Package yaml adds YAML (de)serialization for memfs.FS trees. It's kept
+// out of the memfs package proper so importing memfs never pulls in a YAML
+// dependency for callers who don't need it.
+package yaml
+
+import (
+	"encoding/json"
+	"fmt"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/rjeczalik/tools/fs/memfs"
+)
+
+// Marshal encodes fs as YAML. It reuses FS's JSON representation so the two
+// formats never drift apart.
+func Marshal(fs memfs.FS) ([]byte, error) {
+	p, err := fs.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	var v interface{}
+	if err := json.Unmarshal(p, &v); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(v)
+}
+
+// Unmarshal decodes a YAML tree into fs. It reuses FS's JSON representation,
+// so the accepted shape mirrors memfs.FS.UnmarshalJSON.
+func Unmarshal(p []byte, fs *memfs.FS) error {
+	var v interface{}
+	if err := yaml.Unmarshal(p, &v); err != nil {
+		return err
+	}
+	j, err := json.Marshal(normalize(v))
+	if err != nil {
+		return err
+	}
+	return fs.UnmarshalJSON(j)
+}
+
+// normalize converts the map[interface{}]interface{} that yaml.v2 produces
+// into map[string]interface{}, which encoding/json requires.
+func normalize(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, val := range v {
+			m[fmt.Sprint(k)] = normalize(val)
+		}
+		return m
+	case []interface{}:
+		for i, val := range v {
+			v[i] = normalize(val)
+		}
+		return v
+	default:
+		return v
+	}
+}
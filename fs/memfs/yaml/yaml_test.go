@@ -0,0 +1,28 @@
+package yaml
+
+import (
+	"testing"
+
+	"github.com/rjeczalik/tools/fs/memfs"
+)
+
+func TestMarshalUnmarshalRoundTrip(t *testing.T) {
+	fs := memfs.FS{Tree: memfs.Directory{Children: map[string]interface{}{
+		"file.txt": memfs.File{Content: []byte("hello")},
+	}}}
+	p, err := Marshal(fs)
+	if err != nil {
+		t.Fatalf("Marshal()=%v", err)
+	}
+	var got memfs.FS
+	if err := Unmarshal(p, &got); err != nil {
+		t.Fatalf("Unmarshal()=%v", err)
+	}
+	file, ok := got.Tree.Children["file.txt"].(memfs.File)
+	if !ok {
+		t.Fatalf("got.Tree.Children[\"file.txt\"]=%#v, want memfs.File", got.Tree.Children["file.txt"])
+	}
+	if string(file.Content) != "hello" {
+		t.Errorf("file.Content=%q, want %q", file.Content, "hello")
+	}
+}